@@ -0,0 +1,90 @@
+package main
+
+// DONTCOVER
+// Pruning semantics mirrored from the cosmos-sdk `prune` CLI, see
+// https://github.com/cosmos/cosmos-sdk/tree/main/client/pruning
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/pruning/types"
+)
+
+const (
+	appStoreFlag          = "app-store"
+	pruningFlag           = "pruning"
+	pruningKeepRecentFlag = "pruning-keep-recent"
+	pruningIntervalFlag   = "pruning-interval"
+
+	defaultPruningStrategy   = types.PruningOptionDefault
+	defaultPruningKeepRecent = uint64(0)
+	defaultPruningInterval   = uint64(0)
+)
+
+// pruneAppStoreOptions resolves the pruning strategy requested on the CLI into
+// the pruningtypes.PruningOptions used by the SDK's own pruning config.
+func pruneAppStoreOptions(strategy string, keepRecent, interval uint64) (types.PruningOptions, error) {
+	switch strategy {
+	case types.PruningOptionDefault, "":
+		return types.NewPruningOptions(types.PruningDefault), nil
+	case types.PruningOptionNothing:
+		return types.NewPruningOptions(types.PruningNothing), nil
+	case types.PruningOptionEverything:
+		return types.NewPruningOptions(types.PruningEverything), nil
+	case types.PruningOptionCustom:
+		return types.NewCustomPruningOptions(keepRecent, interval), nil
+	default:
+		return types.PruningOptions{}, fmt.Errorf("unknown pruning strategy %q", strategy)
+	}
+}
+
+// pruneApplicationStore loads the chihuahua app against application.db under
+// dataDir (with every module's store key mounted, so PruneStores has
+// something to act on) and prunes every version below currentHeight-KeepRecent,
+// following the same one-shot semantics as the cosmos-sdk `prune` CLI.
+func pruneApplicationStore(dataDir, homePath string, opts types.PruningOptions) error {
+	keepRecent := int64(opts.KeepRecent)
+	if opts.Strategy == types.PruningNothing || keepRecent <= 0 {
+		fmt.Println("[!] Pruning strategy keeps every version, skipping Application Store pruning")
+		return nil
+	}
+
+	chihuahuaApp, db, err := newOfflineApp(dataDir, homePath, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store, err := rootMultiStore(chihuahuaApp)
+	if err != nil {
+		return err
+	}
+
+	latestVersion := store.GetLatestVersion()
+	retainHeight := latestVersion - keepRecent
+	if retainHeight <= 0 {
+		fmt.Println("[!] Nothing to prune below height", retainHeight)
+		return nil
+	}
+
+	fmt.Println("[!] Pruning Application Store ...")
+	pruningHeights := make([]int64, 0, batchMaxSize)
+	for h := int64(1); h < retainHeight; h++ {
+		pruningHeights = append(pruningHeights, h)
+
+		if len(pruningHeights) == batchMaxSize {
+			if err := store.PruneStores(pruningHeights); err != nil {
+				return err
+			}
+			pruningHeights = pruningHeights[:0]
+		}
+	}
+	if len(pruningHeights) > 0 {
+		if err := store.PruneStores(pruningHeights); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("[!] Compacting Application Store ...")
+	return compactApplicationStore(db)
+}
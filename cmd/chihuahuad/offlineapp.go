@@ -0,0 +1,59 @@
+package main
+
+// DONTCOVER
+// Shared helper for tools in this package that need to read or prune
+// application.db offline, i.e. with the chihuahua app's real IAVL store keys
+// mounted rather than a bare, empty rootmulti.Store.
+
+import (
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	tmlog "github.com/tendermint/tendermint/libs/log"
+	tmdb "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+
+	"github.com/tj327/chihuahua/app"
+)
+
+// newOfflineApp constructs the chihuahua app against application.db under
+// dataDir, mounting every module's store key the same way the running daemon
+// does. loadLatest mirrors baseapp's own flag: pass false when the caller is
+// about to call LoadVersion/LoadVersionForOverwriting itself.
+func newOfflineApp(dataDir, homePath string, loadLatest bool) (*app.ChihuahuaApp, tmdb.DB, error) {
+	db, err := tmdb.NewGoLevelDB("application", dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chihuahuaApp := app.NewChihuahuaApp(
+		tmlog.NewNopLogger(), db, nil, loadLatest, map[int64]bool{}, homePath, 0,
+		app.MakeEncodingConfig(), app.EmptyAppOptions{},
+	)
+
+	return chihuahuaApp, db, nil
+}
+
+// rootMultiStore extracts the *rootmulti.Store backing an offline app so
+// callers can reach APIs (PruneStores, LoadVersion, LoadVersionForOverwriting)
+// that aren't part of the generic sdk store interfaces.
+func rootMultiStore(chihuahuaApp *app.ChihuahuaApp) (*rootmulti.Store, error) {
+	rms, ok := chihuahuaApp.CommitMultiStore().(*rootmulti.Store)
+	if !ok {
+		return nil, fmt.Errorf("application.db's commit multi-store is not a *rootmulti.Store")
+	}
+	return rms, nil
+}
+
+// compactApplicationStore compacts the underlying LevelDB backing an offline
+// application.db once pruning/sharding has finished.
+func compactApplicationStore(db tmdb.DB) error {
+	ldb, ok := db.(interface {
+		CompactRange(r util.Range) error
+	})
+	if !ok {
+		return nil
+	}
+	return ldb.CompactRange(*util.BytesPrefix([]byte{}))
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -43,6 +44,8 @@ func Cmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(NewStartPruneCmd())
+	cmd.AddCommand(NewShardCmd())
+	cmd.AddCommand(NewSnapshotCmd())
 	return cmd
 }
 
@@ -64,6 +67,11 @@ This example keeps blockchain and state data of last 188000 blocks (approximatel
 
 	cmd.Flags().StringP(fullHeight, "f", defaultFullHeight, "Full height to chop to")
 	cmd.Flags().StringP(minHeight, "m", defaultMinHeight, "Min height for ABCI to chop to")
+	cmd.Flags().BoolP(appStoreFlag, "a", true, "Also prune application.db using the cosmos-sdk pruning config below")
+	cmd.Flags().String(pruningFlag, defaultPruningStrategy, "Pruning strategy for application.db (default|nothing|everything|custom)")
+	cmd.Flags().Uint64(pruningKeepRecentFlag, defaultPruningKeepRecent, "Number of recent application.db heights to keep on disk (only used with --pruning=custom)")
+	cmd.Flags().Uint64(pruningIntervalFlag, defaultPruningInterval, "Height interval at which the live node would prune application.db (only used with --pruning=custom)")
+	cmd.Flags().Int64(minRetainBlocksFlag, 0, "Minimum number of recent blocks to keep (caps the retain height at current height minus this), mirrors the server's min-retain-blocks config; 0 disables it")
 	return cmd
 }
 
@@ -78,6 +86,31 @@ func runStartPruneCmd(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	pruneAppStore, err := cmd.Flags().GetBool(appStoreFlag)
+	if err != nil {
+		return err
+	}
+
+	pruningStrategy, err := cmd.Flags().GetString(pruningFlag)
+	if err != nil {
+		return err
+	}
+
+	pruningKeepRecent, err := cmd.Flags().GetUint64(pruningKeepRecentFlag)
+	if err != nil {
+		return err
+	}
+
+	pruningInterval, err := cmd.Flags().GetUint64(pruningIntervalFlag)
+	if err != nil {
+		return err
+	}
+
+	minRetainBlocks, err := cmd.Flags().GetInt64(minRetainBlocksFlag)
+	if err != nil {
+		return err
+	}
+
 	clientCtx := client.GetClientContextFromCmd(cmd)
 	conf := config.DefaultConfig()
 	dbPath := clientCtx.HomeDir + "/" + conf.DBPath
@@ -100,7 +133,9 @@ func runStartPruneCmd(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	startHeight, currentHeight, err := pruneBlockStoreAndGetHeights(dbPath, fullHeight)
+	startHeight, currentHeight, retainHeight, err := pruneBlockStoreAndGetHeights(dbPath, func(currentHeight int64) (int64, error) {
+		return resolveBlockRetentionHeight(dbPath, currentHeight, fullHeight, minRetainBlocks)
+	})
 	if err != nil {
 		return err
 	}
@@ -110,18 +145,32 @@ func runStartPruneCmd(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	err = pruneStateStore(dbPath, startHeight, currentHeight, minHeight, fullHeight)
+	err = pruneStateStore(dbPath, startHeight, currentHeight, minHeight, retainHeight)
 	if err != nil {
 		return err
 	}
+
+	if pruneAppStore {
+		opts, err := pruneAppStoreOptions(pruningStrategy, pruningKeepRecent, pruningInterval)
+		if err != nil {
+			return err
+		}
+
+		if err := pruneApplicationStore(dbPath, clientCtx.HomeDir, opts); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println("[*] Done!")
 
 	return nil
 }
 
-// pruneBlockStoreAndGetHeights prunes blockstore and returns the startHeight and currentHeight.
-func pruneBlockStoreAndGetHeights(dbPath string, fullHeight int64) (
-	startHeight int64, currentHeight int64, err error,
+// pruneBlockStoreAndGetHeights prunes blockstore and returns the startHeight,
+// currentHeight and the retainHeight resolved by resolveRetainHeight, which needs
+// currentHeight to weigh the evidence-age and min-retain-blocks floors.
+func pruneBlockStoreAndGetHeights(dbPath string, resolveRetainHeight func(currentHeight int64) (int64, error)) (
+	startHeight int64, currentHeight int64, retainHeight int64, err error,
 ) {
 	opts := opt.Options{
 		DisableSeeksCompaction: true,
@@ -129,7 +178,7 @@ func pruneBlockStoreAndGetHeights(dbPath string, fullHeight int64) (
 
 	db_bs, err := tmdb.NewGoLevelDBWithOpts("blockstore", dbPath, &opts)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
 	// nolint: staticcheck
@@ -139,10 +188,15 @@ func pruneBlockStoreAndGetHeights(dbPath string, fullHeight int64) (
 	startHeight = bs.Base()
 	currentHeight = bs.Height()
 
+	retainHeight, err = resolveRetainHeight(currentHeight)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
 	fmt.Println("[!] Pruning Block Store ...")
-	prunedBlocks, err := bs.PruneBlocks(currentHeight - fullHeight)
+	prunedBlocks, err := bs.PruneBlocks(retainHeight)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 	fmt.Println("[!] Pruned Block Store ...", prunedBlocks)
 
@@ -151,10 +205,10 @@ func pruneBlockStoreAndGetHeights(dbPath string, fullHeight int64) (
 	// are properly released and any potential error from Close()
 	// is handled. Close() should be idempotent so this is acceptable.
 	if err := db_bs.Close(); err != nil {
-		return 0, 0, err
+		return 0, 0, 0, err
 	}
 
-	return startHeight, currentHeight, nil
+	return startHeight, currentHeight, retainHeight, nil
 }
 
 // compactBlockStore compacts block storage.
@@ -178,8 +232,11 @@ func compactBlockStore(dbPath string) (err error) {
 	return nil
 }
 
-// pruneStateStore prunes and compacts state storage.
-func pruneStateStore(dbPath string, startHeight, currentHeight, minHeight, fullHeight int64) error {
+// pruneStateStore prunes and compacts state storage. retainHeight is the absolute
+// height below which validators/consensus-params are dropped (for `prune start` it
+// comes from resolveBlockRetentionHeight, for `shard` it is the shard's start);
+// ABCI responses keep their own, independent minHeight.
+func pruneStateStore(dbPath string, startHeight, currentHeight, minHeight, retainHeight int64) error {
 	opts := opt.Options{
 		DisableSeeksCompaction: true,
 	}
@@ -199,33 +256,14 @@ func pruneStateStore(dbPath string, startHeight, currentHeight, minHeight, fullH
 		if s == kABCIResponses {
 			retain_height = currentHeight - minHeight
 		} else {
-			retain_height = currentHeight - fullHeight
+			retain_height = retainHeight
 		}
 
-		batch := new(leveldb.Batch)
-		curBatchSize := uint64(0)
-
 		fmt.Println(startHeight, currentHeight, retain_height)
 
-		for c := startHeight; c < retain_height; c++ {
-			batch.Delete([]byte(s + strconv.FormatInt(c, 10)))
-			curBatchSize++
-
-			if curBatchSize%batchMaxSize == 0 && curBatchSize > 0 {
-				err := db.Write(batch, nil)
-				if err != nil {
-					return err
-				}
-				batch.Reset()
-				batch = new(leveldb.Batch)
-			}
-		}
-
-		err := db.Write(batch, nil)
-		if err != nil {
+		if err := pruneKeyPrefix(db, s, retain_height); err != nil {
 			return err
 		}
-		batch.Reset()
 	}
 
 	fmt.Println("[!] Compacting State Store ...")
@@ -234,3 +272,50 @@ func pruneStateStore(dbPath string, startHeight, currentHeight, minHeight, fullH
 	}
 	return nil
 }
+
+// pruneKeyPrefix deletes every key under prefix whose trailing height is below
+// retainHeight. The heights stored under a prefix need not start at the block
+// store's base, so they are discovered by iterating the actual keys rather than
+// by scanning a range of integers below retainHeight.
+func pruneKeyPrefix(db *leveldb.DB, prefix string, retainHeight int64) error {
+	iter := db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	curBatchSize := 0
+
+	for iter.Next() {
+		height, err := strconv.ParseInt(strings.TrimPrefix(string(iter.Key()), prefix), 10, 64)
+		if err != nil {
+			// not a height-suffixed key under this prefix, leave it alone
+			continue
+		}
+
+		if height >= retainHeight {
+			continue
+		}
+
+		batch.Delete(append([]byte(nil), iter.Key()...))
+		curBatchSize++
+
+		if curBatchSize == batchMaxSize {
+			if err := db.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+			curBatchSize = 0
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	if curBatchSize > 0 {
+		if err := db.Write(batch, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+// DONTCOVER
+// Retention-height logic mirrored from Tendermint's (*tendermint/state).GetBlockRetentionHeight,
+// see https://github.com/cosmos/cosmos-sdk/issues/7265
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	tmstateproto "github.com/tendermint/tendermint/proto/tendermint/state"
+)
+
+const minRetainBlocksFlag = "min-retain-blocks"
+
+// resolveBlockRetentionHeight determines the height below which it is safe to prune
+// blockstore.db/state.db. A naive `currentHeight - fullHeight` ignores the chain's
+// own evidence-age window and any operator-supplied floor, so a forced prune could
+// drop blocks the node would still need to verify or submit evidence for if it were
+// restarted. We clamp to the most conservative (smallest) of the three candidates.
+func resolveBlockRetentionHeight(dbPath string, currentHeight, fullHeight, minRetainBlocks int64) (int64, error) {
+	maxAgeNumBlocks, err := loadMaxAgeNumBlocks(dbPath, currentHeight)
+	if err != nil {
+		return 0, err
+	}
+
+	fullHeightCandidate := currentHeight - fullHeight
+
+	retainHeight := fullHeightCandidate
+	fmt.Printf("[!] Candidate retain heights: full-height=%d", fullHeightCandidate)
+
+	if maxAgeNumBlocks > 0 {
+		evidenceCandidate := currentHeight - maxAgeNumBlocks
+		fmt.Printf(", evidence-age=%d", evidenceCandidate)
+		if evidenceCandidate < retainHeight {
+			retainHeight = evidenceCandidate
+		}
+	}
+
+	if minRetainBlocks > 0 {
+		minRetainCandidate := currentHeight - minRetainBlocks
+		fmt.Printf(", min-retain-blocks=%d", minRetainCandidate)
+		if minRetainCandidate < retainHeight {
+			retainHeight = minRetainCandidate
+		}
+	}
+
+	fmt.Println()
+
+	overridden := retainHeight < fullHeightCandidate && fullHeightCandidate > 0
+
+	if retainHeight < 0 {
+		retainHeight = 0
+	}
+
+	if overridden {
+		fmt.Printf("[!] Overriding requested full-height retain point %d with %d to respect evidence age / min-retain-blocks\n", fullHeightCandidate, retainHeight)
+	}
+
+	return retainHeight, nil
+}
+
+// loadMaxAgeNumBlocks reads the consensus params in effect at height from state.db
+// and returns Evidence.MaxAgeNumBlocks, or 0 if no consensus params have been
+// recorded yet.
+func loadMaxAgeNumBlocks(dbPath string, height int64) (int64, error) {
+	opts := opt.Options{
+		DisableSeeksCompaction: true,
+		ReadOnly:               true,
+	}
+
+	db, err := leveldb.OpenFile(dbPath+"/state.db", &opts)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	paramsInfo, err := loadConsensusParamsInfo(db, height)
+	if err != nil {
+		return 0, err
+	}
+	if paramsInfo == nil || paramsInfo.ConsensusParams.Evidence == nil {
+		return 0, nil
+	}
+
+	return paramsInfo.ConsensusParams.Evidence.MaxAgeNumBlocks, nil
+}
+
+// loadConsensusParamsInfo mirrors tendermint's state.Store.LoadConsensusParams:
+// consensus params are only persisted at the height they changed, so a lookup at
+// an arbitrary height may need to follow LastHeightChanged to find the params
+// actually in effect.
+func loadConsensusParamsInfo(db *leveldb.DB, height int64) (*tmstateproto.ConsensusParamsInfo, error) {
+	buf, err := db.Get([]byte(kConsensusParams+strconv.FormatInt(height, 10)), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paramsInfo tmstateproto.ConsensusParamsInfo
+	if err := paramsInfo.Unmarshal(buf); err != nil {
+		return nil, err
+	}
+
+	if paramsInfo.ConsensusParams.Evidence == nil && paramsInfo.LastHeightChanged != height {
+		buf, err = db.Get([]byte(kConsensusParams+strconv.FormatInt(paramsInfo.LastHeightChanged, 10)), nil)
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := paramsInfo.Unmarshal(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	return &paramsInfo, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+// DONTCOVER
+// Inspired by the shard tool in https://github.com/0glabs/0g-chain/blob/main/cmd/0gchaind/cmd/shard.go
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	tmstate "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	tmdb "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/version"
+	"github.com/tendermint/tendermint/config"
+)
+
+const (
+	shardStart        = "start"
+	shardEnd          = "end"
+	onlyAppState      = "only-app-state"
+	onlyCometBFTState = "only-cometbft-state"
+	initialVersion    = "initial-version"
+	noRollbackHeight  = int64(-1)
+)
+
+// NewShardCmd creates a command that trims blockstore.db/state.db down to
+// [start,end] and independently rolls application.db back to end, producing
+// an archive shard of a chihuahua node DB.
+func NewShardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shard",
+		Short: "Keeps only a block range in blockstore.db/state.db and app.db (Make sure your node is down!)",
+		Long: fmt.Sprintf(`Strips everything outside of [start,end] from blockstore.db, state.db and
+application.db.
+
+Use -1 for --end to mean the current height. With --only-app-state, --end must be
+given explicitly since there is no cometBFT height to resolve it from.
+
+Use --only-app-state or --only-cometbft-state to restrict the operation to just one of
+the two stores. --initial-version should only be set when application.db's IAVL stores
+were themselves created with a non-zero initial version (e.g. a chain that launched from
+a non-genesis height); it seeds rootmulti.Store's initial version the same way
+baseapp.SetInitialVersion does, it does not override any app/consensus version check.
+
+Example:
+$ %s prune shard --start 1000000 --end 1500000
+			`, version.AppName),
+		RunE: runShardCmd,
+	}
+
+	cmd.Flags().Int64(shardStart, 0, "Lowest height to retain (inclusive)")
+	cmd.Flags().Int64(shardEnd, noRollbackHeight, "Highest height to retain (inclusive), -1 means latest (requires --only-app-state to be unset)")
+	cmd.Flags().Bool(onlyAppState, false, "Only shard application.db")
+	cmd.Flags().Bool(onlyCometBFTState, false, "Only shard blockstore.db/state.db")
+	cmd.Flags().Uint64(initialVersion, 0, "Initial version application.db's IAVL stores were created with, 0 if they started from height 1")
+	return cmd
+}
+
+func runShardCmd(cmd *cobra.Command, _ []string) error {
+	start, err := cmd.Flags().GetInt64(shardStart)
+	if err != nil {
+		return err
+	}
+
+	end, err := cmd.Flags().GetInt64(shardEnd)
+	if err != nil {
+		return err
+	}
+
+	onlyApp, err := cmd.Flags().GetBool(onlyAppState)
+	if err != nil {
+		return err
+	}
+
+	onlyCometBFT, err := cmd.Flags().GetBool(onlyCometBFTState)
+	if err != nil {
+		return err
+	}
+
+	if onlyApp && onlyCometBFT {
+		return fmt.Errorf("--%s and --%s are mutually exclusive", onlyAppState, onlyCometBFTState)
+	}
+
+	appInitialVersion, err := cmd.Flags().GetUint64(initialVersion)
+	if err != nil {
+		return err
+	}
+
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	conf := config.DefaultConfig()
+	conf.RootDir = clientCtx.HomeDir
+	dbPath := clientCtx.HomeDir + "/" + conf.DBPath
+
+	cmdr := exec.Command("chihuahuad", "status")
+	if err := cmdr.Run(); err == nil {
+		// continue only if throws error, i.e. the node is not running
+		return fmt.Errorf("node appears to be running, stop it before sharding its database")
+	}
+
+	if end == noRollbackHeight && onlyApp {
+		return fmt.Errorf("--%s=-1 has no cometBFT height to resolve against, pass --%s explicitly with --%s", shardEnd, shardEnd, onlyAppState)
+	}
+
+	currentHeight := end
+	if !onlyApp {
+		currentHeight, err = peekBlockStoreHeight(dbPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if end == noRollbackHeight {
+		end = currentHeight
+	}
+
+	if start < 0 || end > currentHeight || start > end {
+		return fmt.Errorf("invalid shard range [%d,%d], current height is %d", start, end, currentHeight)
+	}
+
+	if !onlyApp {
+		if err := shardBlockAndStateStore(conf, dbPath, start, end, currentHeight); err != nil {
+			return err
+		}
+	}
+
+	if !onlyCometBFT {
+		if err := shardAppStore(dbPath, clientCtx.HomeDir, start, end, appInitialVersion); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("[*] Done!")
+	return nil
+}
+
+// peekBlockStoreHeight opens blockstore.db just long enough to read its current
+// height, used both to resolve --end=-1 and to validate the requested range.
+func peekBlockStoreHeight(dbPath string) (int64, error) {
+	opts := opt.Options{
+		DisableSeeksCompaction: true,
+	}
+
+	db_bs, err := tmdb.NewGoLevelDBWithOpts("blockstore", dbPath, &opts)
+	if err != nil {
+		return 0, err
+	}
+	defer db_bs.Close()
+
+	return tmstore.NewBlockStore(db_bs).Height(), nil
+}
+
+// shardBlockAndStateStore first truncates everything above end by repeatedly
+// rolling the cometBFT state and block store back one height at a time via
+// tmstate.Rollback - the same mechanism the upstream `tendermint rollback`/cosmos-sdk
+// `rollback` commands use, rather than hand-deleting blockstore/state.db keys - and
+// then strips the tail before start the same way `prune start` does.
+func shardBlockAndStateStore(conf *config.Config, dbPath string, start, end, currentHeight int64) error {
+	if end < currentHeight {
+		if err := rollbackBlockAndState(conf, currentHeight, end); err != nil {
+			return err
+		}
+	}
+
+	opts := opt.Options{
+		DisableSeeksCompaction: true,
+	}
+
+	db_bs, err := tmdb.NewGoLevelDBWithOpts("blockstore", dbPath, &opts)
+	if err != nil {
+		return err
+	}
+	defer db_bs.Close()
+
+	bs := tmstore.NewBlockStore(db_bs)
+
+	fmt.Println("[!] Sharding Block Store ...")
+	if _, err := bs.PruneBlocks(start); err != nil {
+		return err
+	}
+
+	if err := db_bs.Close(); err != nil {
+		return err
+	}
+
+	if err := compactBlockStore(dbPath); err != nil {
+		return err
+	}
+
+	return pruneStateStore(dbPath, start, end, end-start, start)
+}
+
+// rollbackBlockAndState opens blockstore.db/state.db once and rolls them back
+// from currentHeight to end one height at a time via tmstate.Rollback. The DB
+// handles are kept open across the whole loop rather than reopened per height,
+// since an archive shard can need to roll back millions of heights.
+func rollbackBlockAndState(conf *config.Config, currentHeight, end int64) error {
+	blockStoreDB, err := tmdb.NewDB("blockstore", tmdb.BackendType(conf.DBBackend), conf.DBDir())
+	if err != nil {
+		return err
+	}
+	defer blockStoreDB.Close()
+	blockStore := tmstore.NewBlockStore(blockStoreDB)
+
+	stateDB, err := tmdb.NewDB("state", tmdb.BackendType(conf.DBBackend), conf.DBDir())
+	if err != nil {
+		return err
+	}
+	defer stateDB.Close()
+	stateStore := tmstate.NewStore(stateDB, tmstate.StoreOptions{DiscardABCIResponses: false})
+
+	fmt.Printf("[!] Rolling Block/State Store back from %d to %d ...\n", currentHeight, end)
+	for h := currentHeight; h > end; h-- {
+		if _, _, err := tmstate.Rollback(blockStore, stateStore, true); err != nil {
+			return err
+		}
+		if h%1000 == 0 {
+			fmt.Println("[!] ... at height", h)
+		}
+	}
+
+	return nil
+}
+
+// shardAppStore rolls application.db back to end (deleting committed versions
+// above it, via rootmulti's LoadVersionForOverwriting - the same primitive the
+// cosmos-sdk `rollback` command uses) and separately prunes versions below start,
+// batching PruneStores calls the same way pruneApplicationStore does.
+func shardAppStore(dataDir, homePath string, start, end int64, appInitialVersion uint64) error {
+	chihuahuaApp, db, err := newOfflineApp(dataDir, homePath, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store, err := rootMultiStore(chihuahuaApp)
+	if err != nil {
+		return err
+	}
+
+	if appInitialVersion > 0 {
+		// Must be set before the store is loaded below: rootmulti.Store seeds
+		// each IAVL store's initial version from this field as it is loaded,
+		// the same way baseapp's own SetInitialVersion option is applied before
+		// LoadLatestVersion.
+		if err := store.SetInitialVersion(int64(appInitialVersion)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("[!] Sharding Application Store ...")
+	if err := store.LoadVersionForOverwriting(end); err != nil {
+		return err
+	}
+
+	pruningHeights := make([]int64, 0, batchMaxSize)
+	for h := int64(1); h < start; h++ {
+		pruningHeights = append(pruningHeights, h)
+
+		if len(pruningHeights) == batchMaxSize {
+			if err := store.PruneStores(pruningHeights); err != nil {
+				return err
+			}
+			pruningHeights = pruningHeights[:0]
+		}
+	}
+	if len(pruningHeights) > 0 {
+		if err := store.PruneStores(pruningHeights); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("[!] Compacting Application Store ...")
+	return compactApplicationStore(db)
+}
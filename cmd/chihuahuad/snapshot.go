@@ -0,0 +1,249 @@
+package main
+
+// DONTCOVER
+// Snapshot lifecycle mirrored from the cosmos-sdk snapshots.Manager introduced
+// in https://github.com/cosmos/cosmos-sdk/pull/11496
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	tmlog "github.com/tendermint/tendermint/libs/log"
+	tmdb "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/snapshots"
+	snapshottypes "github.com/cosmos/cosmos-sdk/snapshots/types"
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+
+	"github.com/tj327/chihuahua/app"
+)
+
+const (
+	snapshotHeight     = "height"
+	snapshotKeepRecent = "keep-recent"
+)
+
+// NewSnapshotCmd creates a command for driving the cosmos-sdk snapshots.Manager
+// against an offline application.db, so operators can pair `prune start`/`shard`
+// with a small set of verified state-sync snapshots.
+func NewSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                        "snapshot",
+		Short:                      "Creates, lists, prunes and restores state-sync snapshots (Make sure your node is down!)",
+		SuggestionsMinimumDistance: 2,
+		RunE:                       client.ValidateCmd,
+	}
+
+	cmd.AddCommand(NewSnapshotCreateCmd())
+	cmd.AddCommand(NewSnapshotListCmd())
+	cmd.AddCommand(NewSnapshotPruneCmd())
+	cmd.AddCommand(NewSnapshotRestoreCmd())
+	return cmd
+}
+
+func NewSnapshotCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Creates a state-sync snapshot at the given height",
+		RunE:  runSnapshotCreateCmd,
+	}
+	cmd.Flags().Int64(snapshotHeight, 0, "Height to snapshot, 0 means the latest committed height")
+	return cmd
+}
+
+func NewSnapshotListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the snapshots retained under <home>/data/snapshots",
+		RunE:  runSnapshotListCmd,
+	}
+}
+
+func NewSnapshotPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Deletes every snapshot except the most recent --keep-recent",
+		RunE:  runSnapshotPruneCmd,
+	}
+	cmd.Flags().Uint32(snapshotKeepRecent, 2, "Number of most recent snapshots to retain")
+	return cmd
+}
+
+func NewSnapshotRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Rehydrates application.db from a previously created snapshot",
+		RunE:  runSnapshotRestoreCmd,
+	}
+	cmd.Flags().Int64(snapshotHeight, 0, "Height of the snapshot to restore")
+	return cmd
+}
+
+// openSnapshotManager wires the chihuahua app's multistore into a
+// snapshots.Manager rooted at <home>/data/snapshots, for offline use. The app
+// itself is left unloaded (loadLatest=false); callers that need to read store
+// data (create) must load it at the desired height themselves once that
+// height is known.
+func openSnapshotManager(clientHomeDir string) (*snapshots.Manager, *app.ChihuahuaApp, tmdb.DB, error) {
+	dataDir := clientHomeDir + "/data"
+
+	db, err := tmdb.NewGoLevelDB("application", dataDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	logger := tmlog.NewNopLogger()
+	chihuahuaApp := app.NewChihuahuaApp(
+		logger, db, nil, false, map[int64]bool{}, clientHomeDir, 0,
+		app.MakeEncodingConfig(), app.EmptyAppOptions{},
+	)
+
+	// The metadata store must be durable across invocations: every CLI run is a
+	// fresh process, so a snapshot registered by `create` needs to still be
+	// there for a later `list`/`prune`/`restore` to find.
+	metadataDB, err := tmdb.NewGoLevelDB("metadata", dataDir+"/snapshots")
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	snapshotStore, err := snapshots.NewStore(metadataDB, dataDir+"/snapshots")
+	if err != nil {
+		db.Close()
+		return nil, nil, nil, err
+	}
+
+	manager := snapshots.NewManager(snapshotStore, snapshottypes.SnapshotOptions{}, chihuahuaApp.CommitMultiStore().(snapshottypes.Snapshotter), nil, logger)
+	return manager, chihuahuaApp, db, nil
+}
+
+func runSnapshotCreateCmd(cmd *cobra.Command, _ []string) error {
+	height, err := cmd.Flags().GetInt64(snapshotHeight)
+	if err != nil {
+		return err
+	}
+
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	manager, chihuahuaApp, db, err := openSnapshotManager(clientCtx.HomeDir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if height == 0 {
+		height = rootmulti.GetLatestVersion(db)
+	}
+
+	// manager.Create reads the IAVL tree at `height` through the app's
+	// multistore, so the app has to actually be loaded at that version first.
+	if err := chihuahuaApp.LoadHeight(height); err != nil {
+		return err
+	}
+
+	fmt.Println("[!] Creating snapshot at height", height, "...")
+	snapshot, err := manager.Create(uint64(height))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[*] Created snapshot at height %d (format %d, %d chunks)\n", snapshot.Height, snapshot.Format, len(snapshot.Metadata.ChunkHashes))
+	return nil
+}
+
+func runSnapshotListCmd(cmd *cobra.Command, _ []string) error {
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	manager, _, db, err := openSnapshotManager(clientCtx.HomeDir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshotList, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshotList {
+		fmt.Printf("height=%d format=%d chunks=%d\n", s.Height, s.Format, s.Chunks)
+	}
+	return nil
+}
+
+func runSnapshotPruneCmd(cmd *cobra.Command, _ []string) error {
+	keepRecent, err := cmd.Flags().GetUint32(snapshotKeepRecent)
+	if err != nil {
+		return err
+	}
+
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	manager, _, db, err := openSnapshotManager(clientCtx.HomeDir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshotList, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(snapshotList, func(i, j int) bool { return snapshotList[i].Height > snapshotList[j].Height })
+
+	if uint32(len(snapshotList)) <= keepRecent {
+		fmt.Println("[*] Nothing to prune")
+		return nil
+	}
+
+	fmt.Println("[!] Pruning snapshots, keeping the most recent", keepRecent, "...")
+	for _, s := range snapshotList[keepRecent:] {
+		if err := manager.Delete(uint64(s.Height), s.Format); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("[*] Done!")
+	return nil
+}
+
+func runSnapshotRestoreCmd(cmd *cobra.Command, _ []string) error {
+	height, err := cmd.Flags().GetInt64(snapshotHeight)
+	if err != nil {
+		return err
+	}
+
+	clientCtx := client.GetClientContextFromCmd(cmd)
+	manager, _, db, err := openSnapshotManager(clientCtx.HomeDir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshotList, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	var target *snapshottypes.Snapshot
+	for _, s := range snapshotList {
+		if int64(s.Height) == height {
+			target = s
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no snapshot found at height %d, run 'snapshot list' to see what's available", height)
+	}
+
+	// Unlike create, restore populates the multistore from the snapshot chunks
+	// itself, so the app is intentionally left unloaded going into this call.
+	fmt.Println("[!] Restoring application.db from snapshot at height", height, "...")
+	if err := manager.Restore(*target); err != nil {
+		return err
+	}
+
+	fmt.Println("[*] Done!")
+	return nil
+}